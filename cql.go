@@ -0,0 +1,53 @@
+package cassandrastore
+
+import "github.com/gocql/gocql"
+
+// cqlSession is the subset of *gocql.Session that CassandraStore depends on.
+// It exists so tests can substitute a fake in place of a real Cassandra
+// connection; gocqlSession adapts a real *gocql.Session to it.
+type cqlSession interface {
+	Query(stmt string, values ...interface{}) cqlQuery
+	Close()
+}
+
+// cqlQuery is the subset of *gocql.Query that CassandraStore depends on.
+type cqlQuery interface {
+	Scan(dest ...interface{}) error
+	Exec() error
+	Iter() cqlIter
+	Consistency(gocql.Consistency) cqlQuery
+	SerialConsistency(gocql.SerialConsistency) cqlQuery
+}
+
+// cqlIter is the subset of *gocql.Iter that CassandraStore depends on.
+// *gocql.Iter already satisfies this interface, so it needs no adapter.
+type cqlIter interface {
+	Scan(dest ...interface{}) bool
+	Close() error
+}
+
+// gocqlSession adapts a real *gocql.Session to cqlSession.
+type gocqlSession struct {
+	*gocql.Session
+}
+
+func (s gocqlSession) Query(stmt string, values ...interface{}) cqlQuery {
+	return gocqlQuery{s.Session.Query(stmt, values...)}
+}
+
+// gocqlQuery adapts a real *gocql.Query to cqlQuery.
+type gocqlQuery struct {
+	*gocql.Query
+}
+
+func (q gocqlQuery) Consistency(c gocql.Consistency) cqlQuery {
+	return gocqlQuery{q.Query.Consistency(c)}
+}
+
+func (q gocqlQuery) SerialConsistency(c gocql.SerialConsistency) cqlQuery {
+	return gocqlQuery{q.Query.SerialConsistency(c)}
+}
+
+func (q gocqlQuery) Iter() cqlIter {
+	return q.Query.Iter()
+}
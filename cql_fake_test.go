@@ -0,0 +1,200 @@
+package cassandrastore
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gocql/gocql"
+	"github.com/gorilla/securecookie"
+	"github.com/gorilla/sessions"
+)
+
+// fakeRow is one row of a fakeSession's in-memory sessions table.
+type fakeRow struct {
+	values       []byte
+	lastAccessed time.Time
+}
+
+// fakeSession is a minimal in-memory stand-in for a *gocql.Session, used to
+// exercise CassandraStore's query logic without a real Cassandra cluster.
+// It understands just enough of the statements CassandraStore issues
+// (recognized by prefix) to fake their effect on an in-memory table.
+type fakeSession struct {
+	rows   map[string]fakeRow
+	closed bool
+	// legacySchema, when true, simulates a sessions table created before
+	// the last_accessed column existed (id text PRIMARY KEY, values
+	// blob): any statement that references last_accessed fails the way a
+	// real Cassandra cluster would, with "undefined column name".
+	legacySchema bool
+	// execs records every statement passed to Exec, in order, for tests
+	// that just want to assert on what CassandraStore issued (e.g. schema
+	// DDL) rather than on a table-mutating effect.
+	execs []string
+	// consistencies records every (statement, consistency) pair passed to
+	// Consistency, so tests can confirm CassandraStore actually applies
+	// ReadConsistency/WriteConsistency rather than silently dropping them.
+	consistencies []consistencyCall
+}
+
+type consistencyCall struct {
+	stmt        string
+	consistency gocql.Consistency
+}
+
+func newFakeSession() *fakeSession {
+	return &fakeSession{rows: make(map[string]fakeRow)}
+}
+
+func (f *fakeSession) Query(stmt string, values ...interface{}) cqlQuery {
+	return &fakeQuery{session: f, stmt: stmt, values: values}
+}
+
+func (f *fakeSession) Close() {
+	f.closed = true
+}
+
+// fakeQuery is the cqlQuery returned by fakeSession.Query.
+type fakeQuery struct {
+	session *fakeSession
+	stmt    string
+	values  []interface{}
+}
+
+func (q *fakeQuery) Consistency(c gocql.Consistency) cqlQuery {
+	q.session.consistencies = append(q.session.consistencies, consistencyCall{stmt: q.stmt, consistency: c})
+	return q
+}
+
+func (q *fakeQuery) SerialConsistency(gocql.SerialConsistency) cqlQuery { return q }
+
+// errUndefinedColumn mimics the CQL error a real Cassandra cluster returns
+// when a statement references a column the table doesn't have, e.g. a
+// sessions table predating the last_accessed column.
+func errUndefinedColumn(col string) error {
+	return fmt.Errorf("undefined column name %s", col)
+}
+
+func (q *fakeQuery) Scan(dest ...interface{}) error {
+	if q.session.legacySchema && strings.Contains(q.stmt, "last_accessed") {
+		return errUndefinedColumn("last_accessed")
+	}
+
+	switch {
+	case strings.HasPrefix(q.stmt, "SELECT values FROM"):
+		row, ok := q.session.rows[q.values[0].(string)]
+		if !ok {
+			return gocql.ErrNotFound
+		}
+		*dest[0].(*[]byte) = row.values
+		return nil
+	default:
+		return fmt.Errorf("fakeQuery: unsupported Scan query: %s", q.stmt)
+	}
+}
+
+func (q *fakeQuery) Exec() error {
+	q.session.execs = append(q.session.execs, q.stmt)
+
+	if q.session.legacySchema && strings.Contains(q.stmt, "last_accessed") {
+		return errUndefinedColumn("last_accessed")
+	}
+
+	switch {
+	case strings.HasPrefix(q.stmt, "CREATE KEYSPACE"), strings.HasPrefix(q.stmt, "CREATE TABLE"):
+		return nil
+	case strings.HasPrefix(q.stmt, "INSERT INTO") && strings.Contains(q.stmt, "last_accessed"):
+		id := q.values[0].(string)
+		q.session.rows[id] = fakeRow{
+			values:       q.values[1].([]byte),
+			lastAccessed: q.values[2].(time.Time),
+		}
+		return nil
+	case strings.HasPrefix(q.stmt, "INSERT INTO"):
+		id := q.values[0].(string)
+		q.session.rows[id] = fakeRow{values: q.values[1].([]byte)}
+		return nil
+	case strings.HasPrefix(q.stmt, "DELETE FROM"):
+		id := q.values[0].(string)
+		if _, ok := q.session.rows[id]; !ok {
+			return gocql.ErrNotFound
+		}
+		delete(q.session.rows, id)
+		return nil
+	case strings.HasPrefix(q.stmt, "UPDATE"):
+		// refreshQuery: USING TTL ? SET values = ?, last_accessed = ? WHERE id = ?
+		id := q.values[3].(string)
+		q.session.rows[id] = fakeRow{
+			values:       q.values[1].([]byte),
+			lastAccessed: q.values[2].(time.Time),
+		}
+		return nil
+	default:
+		return fmt.Errorf("fakeQuery: unsupported Exec query: %s", q.stmt)
+	}
+}
+
+func (q *fakeQuery) Iter() cqlIter {
+	if q.session.closed {
+		return &fakeIter{err: fmt.Errorf("fake: session closed")}
+	}
+	if q.session.legacySchema && strings.Contains(q.stmt, "last_accessed") {
+		return &fakeIter{err: errUndefinedColumn("last_accessed")}
+	}
+
+	ids := make([]string, 0, len(q.session.rows))
+	for id := range q.session.rows {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return &fakeIter{session: q.session, ids: ids}
+}
+
+// fakeIter is the cqlIter returned by fakeQuery.Iter, used by gc.go's sweep.
+type fakeIter struct {
+	session *fakeSession
+	ids     []string
+	pos     int
+	err     error
+}
+
+func (it *fakeIter) Scan(dest ...interface{}) bool {
+	if it.err != nil || it.pos >= len(it.ids) {
+		return false
+	}
+	id := it.ids[it.pos]
+	it.pos++
+	row := it.session.rows[id]
+	*dest[0].(*string) = id
+	*dest[1].(*time.Time) = row.lastAccessed
+	return true
+}
+
+func (it *fakeIter) Close() error { return it.err }
+
+// consistencyPtr is a helper for tests that need to point at a
+// gocql.Consistency value, since ReadConsistency/WriteConsistency are
+// *gocql.Consistency fields (see applyConsistency for why).
+func consistencyPtr(c gocql.Consistency) *gocql.Consistency {
+	return &c
+}
+
+// newTestStore returns a *CassandraStore backed by a fresh fakeSession,
+// wired up the same way the real constructors wire up a *gocql.Session.
+func newTestStore() (*CassandraStore, *fakeSession) {
+	fs := newFakeSession()
+	c := &CassandraStore{
+		Codecs: securecookie.CodecsFromPairs([]byte("01234567890123456789012345678901")),
+		Options: &sessions.Options{
+			Path:   "/",
+			MaxAge: 2592000,
+		},
+		TableName:  "sessions",
+		Serializer: GobSerializer{},
+		db:         fs,
+	}
+	c.setQueries()
+	return c, fs
+}
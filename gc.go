@@ -0,0 +1,73 @@
+package cassandrastore
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// GC performs periodic sweeps of the sessions table, deleting any session
+// whose last_accessed column is older than c.GCMaxLifetime. It is modeled
+// on Beego's session provider GC, and exists because Cassandra's own TTL
+// leaves tombstones behind on a high-churn sessions table; GC gives
+// deployments a bounded, last-accessed-based expiry instead.
+//
+// GC blocks until ctx is done, and is a no-op if c.GCMaxLifetime is zero.
+// Any error from a sweep is passed to c.GCErrorHandler, if set, rather than
+// stopping the loop. Most callers should use StartGC rather than calling GC
+// directly.
+func (c *CassandraStore) GC(ctx context.Context, interval time.Duration) {
+	if c.GCMaxLifetime <= 0 {
+		return
+	}
+
+	t := time.NewTicker(interval)
+	defer t.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+			if err := c.sweep(); err != nil && c.GCErrorHandler != nil {
+				c.GCErrorHandler(err)
+			}
+		}
+	}
+}
+
+// StartGC runs GC in a background goroutine, and returns a func that stops
+// it. Callers should invoke the returned func on shutdown to release the
+// goroutine.
+func (c *CassandraStore) StartGC(ctx context.Context, interval time.Duration) context.CancelFunc {
+	ctx, cancel := context.WithCancel(ctx)
+	go c.GC(ctx, interval)
+	return cancel
+}
+
+// sweep runs a single pass over the sessions table, deleting rows whose
+// last_accessed predates c.GCMaxLifetime. It applies c.ReadConsistency to
+// the scan and c.WriteConsistency to each delete, same as load/save/delete,
+// and stops at (and returns) the first error it hits.
+func (c *CassandraStore) sweep() error {
+	cutoff := c.clock().Add(-c.GCMaxLifetime)
+
+	var id string
+	var lastAccessed time.Time
+	iter := c.applyConsistency(c.db.Query(c.gcSelectQuery), c.ReadConsistency).Iter()
+	for iter.Scan(&id, &lastAccessed) {
+		if !lastAccessed.Before(cutoff) {
+			continue
+		}
+		q := c.applyConsistency(c.db.Query(c.deleteQuery, id), c.WriteConsistency)
+		if err := q.Exec(); err != nil {
+			iter.Close()
+			return fmt.Errorf("cassandrastore: gc: %v", err.Error())
+		}
+	}
+
+	if err := iter.Close(); err != nil {
+		return fmt.Errorf("cassandrastore: gc: %v", err.Error())
+	}
+	return nil
+}
@@ -0,0 +1,137 @@
+package cassandrastore
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gocql/gocql"
+)
+
+func TestSweepDeletesOnlyExpiredSessions(t *testing.T) {
+	c, fs := newTestStore()
+	c.GCMaxLifetime = time.Hour
+
+	// Fake clock: now starts at a fixed instant so the test doesn't depend
+	// on wall-clock time.
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	c.now = func() time.Time { return now }
+
+	fs.rows["stale"] = fakeRow{values: []byte("stale"), lastAccessed: now.Add(-2 * time.Hour)}
+	fs.rows["fresh"] = fakeRow{values: []byte("fresh"), lastAccessed: now.Add(-time.Minute)}
+
+	if err := c.sweep(); err != nil {
+		t.Fatalf("sweep: %v", err)
+	}
+
+	if _, ok := fs.rows["stale"]; ok {
+		t.Error("sweep did not delete a session past GCMaxLifetime")
+	}
+	if _, ok := fs.rows["fresh"]; !ok {
+		t.Error("sweep deleted a session within GCMaxLifetime")
+	}
+}
+
+func TestSweepAppliesConsistency(t *testing.T) {
+	c, fs := newTestStore()
+	c.GCMaxLifetime = time.Hour
+	c.ReadConsistency = consistencyPtr(gocql.LocalOne)
+	c.WriteConsistency = consistencyPtr(gocql.LocalQuorum)
+
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	c.now = func() time.Time { return now }
+	fs.rows["stale"] = fakeRow{values: []byte("stale"), lastAccessed: now.Add(-2 * time.Hour)}
+
+	if err := c.sweep(); err != nil {
+		t.Fatalf("sweep: %v", err)
+	}
+
+	var sawRead, sawWrite bool
+	for _, call := range fs.consistencies {
+		switch call.consistency {
+		case gocql.LocalOne:
+			sawRead = true
+		case gocql.LocalQuorum:
+			sawWrite = true
+		}
+	}
+	if !sawRead {
+		t.Error("sweep's scan did not apply ReadConsistency")
+	}
+	if !sawWrite {
+		t.Error("sweep's delete did not apply WriteConsistency")
+	}
+}
+
+// TestSweepAppliesConsistencyAny guards against a regression where
+// applyConsistency used "consistency != 0" as its unset sentinel: since
+// gocql.Any == 0, that made an explicit gocql.Any indistinguishable from an
+// unset ReadConsistency/WriteConsistency, and silently dropped it.
+func TestSweepAppliesConsistencyAny(t *testing.T) {
+	c, fs := newTestStore()
+	c.GCMaxLifetime = time.Hour
+	c.WriteConsistency = consistencyPtr(gocql.Any)
+
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	c.now = func() time.Time { return now }
+	fs.rows["stale"] = fakeRow{values: []byte("stale"), lastAccessed: now.Add(-2 * time.Hour)}
+
+	if err := c.sweep(); err != nil {
+		t.Fatalf("sweep: %v", err)
+	}
+
+	var applied bool
+	for _, call := range fs.consistencies {
+		if strings.HasPrefix(call.stmt, "DELETE FROM") && call.consistency == gocql.Any {
+			applied = true
+		}
+	}
+	if !applied {
+		t.Error("sweep's delete did not apply an explicit gocql.Any WriteConsistency")
+	}
+}
+
+func TestGCNoopWhenMaxLifetimeUnset(t *testing.T) {
+	c, _ := newTestStore()
+
+	done := make(chan struct{})
+	go func() {
+		c.GC(context.Background(), time.Millisecond)
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("GC did not return immediately when GCMaxLifetime is unset")
+	}
+}
+
+func TestGCReportsSweepErrors(t *testing.T) {
+	c, fs := newTestStore()
+	c.GCMaxLifetime = time.Hour
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	c.now = func() time.Time { return now }
+	fs.rows["stale"] = fakeRow{values: []byte("stale"), lastAccessed: now.Add(-2 * time.Hour)}
+
+	// Close the session out from under GC so the scan it issues fails,
+	// and confirm that failure reaches GCErrorHandler instead of being
+	// silently swallowed.
+	fs.closed = true
+	errs := make(chan error, 1)
+	c.GCErrorHandler = func(err error) { errs <- err }
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go c.GC(ctx, time.Millisecond)
+
+	select {
+	case err := <-errs:
+		if err == nil {
+			t.Fatal("GCErrorHandler called with nil error")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("GCErrorHandler was never called for a failing sweep")
+	}
+}
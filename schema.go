@@ -0,0 +1,113 @@
+package cassandrastore
+
+import (
+	"fmt"
+
+	"github.com/gocql/gocql"
+)
+
+// EnsureSchema creates keyspace, if it does not already exist, using the
+// supplied replication strategy, and then creates the store's sessions
+// table within it if that does not already exist either. The table is
+// created as keyspace.TableName, schema-qualified, so this works whether or
+// not c's session has keyspace selected.
+//
+// replication is passed straight through to CREATE KEYSPACE's WITH
+// replication clause, so it must contain a "class" key naming the
+// replication strategy (e.g. "SimpleStrategy" or
+// "NetworkTopologyStrategy") along with whatever factor keys that
+// strategy requires, e.g.:
+//
+//	c.EnsureSchema("myapp", map[string]interface{}{
+//		"class":              "SimpleStrategy",
+//		"replication_factor": 3,
+//	})
+//
+// EnsureSchema is intended to be called once, at application start-up; it
+// is not on the hot path for Get/New/Save.
+//
+// keyspace and replication's values are spliced directly into the
+// CREATE KEYSPACE/CREATE TABLE statements without quoting or escaping,
+// so both must be trusted, compile-time-constant-like values — never
+// build them from end-user input.
+//
+// A brand new keyspace cannot be bootstrapped through c alone: c.db was
+// already connected via a ClusterConfig, and gocql's CreateSession issues a
+// "USE <keyspace>" for ClusterConfig.Keyspace that fails outright if that
+// keyspace doesn't exist yet, so EnsureSchema is never reached in that case.
+// Use NewCassandraStoreWithSchema instead when keyspace may not exist yet;
+// reach for EnsureSchema directly only once the keyspace is already there
+// (e.g. to add the table for a new TableName in an existing keyspace).
+func (c *CassandraStore) EnsureSchema(keyspace string, replication map[string]interface{}) error {
+	q := fmt.Sprintf(`CREATE KEYSPACE IF NOT EXISTS %s WITH replication = %s`, keyspace, formatReplication(replication))
+	if err := c.db.Query(q).Exec(); err != nil {
+		return fmt.Errorf("cassandrastore: %v", err.Error())
+	}
+
+	q = fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s.%s (id text PRIMARY KEY, values blob, last_accessed timestamp)`, keyspace, c.TableName)
+	if err := c.db.Query(q).Exec(); err != nil {
+		return fmt.Errorf("cassandrastore: %v", err.Error())
+	}
+
+	return nil
+}
+
+// NewCassandraStoreWithSchema bootstraps keyspace (and the sessions table
+// within it) if they don't already exist, then returns a *CassandraStore
+// connected to that keyspace. Unlike calling EnsureSchema on a store
+// returned by NewCassandraStore, this works even when keyspace does not
+// exist yet: it first opens a short-lived session with no keyspace
+// selected to run the CREATE KEYSPACE/CREATE TABLE statements, closes it,
+// then opens the real, long-lived session against config with Keyspace set
+// to keyspace.
+//
+// keyspace and replication are passed straight through to EnsureSchema, so
+// the same caveat applies: both must be trusted values, never built from
+// end-user input.
+func NewCassandraStoreWithSchema(config *gocql.ClusterConfig, keyspace string, replication map[string]interface{}, tableName string, keypairs ...[]byte) (*CassandraStore, error) {
+	if tableName == "" {
+		tableName = "sessions"
+	}
+
+	bootstrapCfg := *config
+	bootstrapCfg.Keyspace = ""
+	bootstrapDB, err := bootstrapCfg.CreateSession()
+	if err != nil {
+		return nil, fmt.Errorf("cassandrastore: %v", err.Error())
+	}
+	defer bootstrapDB.Close()
+
+	bootstrap := &CassandraStore{TableName: tableName, db: gocqlSession{bootstrapDB}}
+	if err := bootstrap.EnsureSchema(keyspace, replication); err != nil {
+		return nil, err
+	}
+
+	scopedCfg := *config
+	scopedCfg.Keyspace = keyspace
+	return NewCassandraStore(&scopedCfg, tableName, keypairs...)
+}
+
+// formatReplication renders a replication strategy map as the CQL map
+// literal CREATE KEYSPACE expects, e.g.
+// {'class': 'SimpleStrategy', 'replication_factor': 3}. It does no
+// quoting or escaping of its own, so replication must come from trusted,
+// compile-time-constant-like values (see EnsureSchema).
+func formatReplication(replication map[string]interface{}) string {
+	s := "{"
+	first := true
+	for k, v := range replication {
+		if !first {
+			s += ", "
+		}
+		first = false
+
+		switch val := v.(type) {
+		case string:
+			s += fmt.Sprintf("'%s': '%s'", k, val)
+		default:
+			s += fmt.Sprintf("'%s': %v", k, val)
+		}
+	}
+	s += "}"
+	return s
+}
@@ -0,0 +1,40 @@
+package cassandrastore
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestFormatReplication(t *testing.T) {
+	got := formatReplication(map[string]interface{}{"class": "SimpleStrategy"})
+	want := `{'class': 'SimpleStrategy'}`
+	if got != want {
+		t.Fatalf("formatReplication = %q, want %q", got, want)
+	}
+}
+
+// TestEnsureSchemaQualifiesTableWithKeyspace guards against a regression of
+// the keyspace bootstrap bug: CREATE TABLE must name its keyspace
+// explicitly, since EnsureSchema may run over a session that has no
+// keyspace selected (see NewCassandraStoreWithSchema).
+func TestEnsureSchemaQualifiesTableWithKeyspace(t *testing.T) {
+	fs := newFakeSession()
+	c := &CassandraStore{TableName: "sessions", db: fs}
+
+	if err := c.EnsureSchema("myapp", map[string]interface{}{
+		"class":              "SimpleStrategy",
+		"replication_factor": 3,
+	}); err != nil {
+		t.Fatalf("EnsureSchema: %v", err)
+	}
+
+	if len(fs.execs) != 2 {
+		t.Fatalf("execs = %v, want 2 statements", fs.execs)
+	}
+	if !strings.HasPrefix(fs.execs[0], "CREATE KEYSPACE IF NOT EXISTS myapp ") {
+		t.Fatalf("execs[0] = %q, want a CREATE KEYSPACE for myapp", fs.execs[0])
+	}
+	if !strings.Contains(fs.execs[1], "CREATE TABLE IF NOT EXISTS myapp.sessions ") {
+		t.Fatalf("execs[1] = %q, want a schema-qualified CREATE TABLE", fs.execs[1])
+	}
+}
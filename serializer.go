@@ -0,0 +1,73 @@
+package cassandrastore
+
+import (
+	"bytes"
+	"encoding/gob"
+	"encoding/json"
+	"fmt"
+
+	"github.com/gorilla/sessions"
+)
+
+// SessionSerializer encodes and decodes the values stored in a
+// sessions.Session to and from the bytes persisted in the sessions table.
+// CassandraStore defaults to GobSerializer, but a JSONSerializer is also
+// provided for interoperability with non-Go readers of the sessions table.
+type SessionSerializer interface {
+	Serialize(s *sessions.Session) ([]byte, error)
+	Deserialize(d []byte, s *sessions.Session) error
+}
+
+// GobSerializer encodes session values using encoding/gob. This is
+// CassandraStore's default serializer.
+type GobSerializer struct{}
+
+func (GobSerializer) Serialize(s *sessions.Session) ([]byte, error) {
+	buf := new(bytes.Buffer)
+	enc := gob.NewEncoder(buf)
+	if err := enc.Encode(s.Values); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (GobSerializer) Deserialize(d []byte, s *sessions.Session) error {
+	dec := gob.NewDecoder(bytes.NewBuffer(d))
+	return dec.Decode(&s.Values)
+}
+
+// JSONSerializer encodes session values as JSON. It is not a drop-in
+// replacement for GobSerializer: it only round-trips keys that are strings,
+// since encoding/json cannot marshal the interface{} keys gob allows into
+// JSON object keys, and — more easily missed — it does not preserve Go
+// numeric types. encoding/json decodes every JSON number into float64, so a
+// value stored as an int, int64, etc. comes back out of Deserialize as a
+// float64, not the original type. Code that does e.g.
+// s.Values["uid"].(int) after a save/load cycle will panic under
+// JSONSerializer even though the identical code works fine under
+// GobSerializer. Switching a store's Serializer to JSONSerializer therefore
+// requires auditing every type assertion on a numeric session value.
+type JSONSerializer struct{}
+
+func (JSONSerializer) Serialize(s *sessions.Session) ([]byte, error) {
+	m := make(map[string]interface{}, len(s.Values))
+	for k, v := range s.Values {
+		ks, ok := k.(string)
+		if !ok {
+			return nil, fmt.Errorf("cassandrastore: non-string key %v, cannot serialize session to JSON", k)
+		}
+		m[ks] = v
+	}
+	return json.Marshal(m)
+}
+
+func (JSONSerializer) Deserialize(d []byte, s *sessions.Session) error {
+	m := make(map[string]interface{})
+	if err := json.Unmarshal(d, &m); err != nil {
+		return err
+	}
+	for k, v := range m {
+		s.Values[k] = v
+	}
+	return nil
+}
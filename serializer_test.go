@@ -0,0 +1,60 @@
+package cassandrastore
+
+import (
+	"testing"
+
+	"github.com/gorilla/sessions"
+)
+
+func TestGobSerializerPreservesNumericType(t *testing.T) {
+	s := sessions.NewSession(nil, "test")
+	s.Values["uid"] = 42
+
+	d, err := (GobSerializer{}).Serialize(s)
+	if err != nil {
+		t.Fatalf("Serialize: %v", err)
+	}
+
+	out := sessions.NewSession(nil, "test")
+	if err := (GobSerializer{}).Deserialize(d, out); err != nil {
+		t.Fatalf("Deserialize: %v", err)
+	}
+
+	if _, ok := out.Values["uid"].(int); !ok {
+		t.Fatalf("uid = %v (%T), want int", out.Values["uid"], out.Values["uid"])
+	}
+}
+
+// TestJSONSerializerDoesNotPreserveNumericType documents the JSONSerializer
+// footgun called out in its doc comment: a value stored as an int comes
+// back as a float64, unlike GobSerializer.
+func TestJSONSerializerDoesNotPreserveNumericType(t *testing.T) {
+	s := sessions.NewSession(nil, "test")
+	s.Values["uid"] = 42
+
+	d, err := (JSONSerializer{}).Serialize(s)
+	if err != nil {
+		t.Fatalf("Serialize: %v", err)
+	}
+
+	out := sessions.NewSession(nil, "test")
+	if err := (JSONSerializer{}).Deserialize(d, out); err != nil {
+		t.Fatalf("Deserialize: %v", err)
+	}
+
+	if _, ok := out.Values["uid"].(int); ok {
+		t.Fatalf("uid round-tripped as int; expected the documented float64 footgun")
+	}
+	if _, ok := out.Values["uid"].(float64); !ok {
+		t.Fatalf("uid = %v (%T), want float64", out.Values["uid"], out.Values["uid"])
+	}
+}
+
+func TestJSONSerializerRejectsNonStringKeys(t *testing.T) {
+	s := sessions.NewSession(nil, "test")
+	s.Values[42] = "value"
+
+	if _, err := (JSONSerializer{}).Serialize(s); err == nil {
+		t.Fatal("Serialize: expected error for non-string key, got nil")
+	}
+}
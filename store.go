@@ -1,12 +1,11 @@
 package cassandrastore
 
 import (
-	"bytes"
 	"encoding/base32"
-	"encoding/gob"
 	"fmt"
 	"net/http"
 	"strings"
+	"time"
 
 	"github.com/gocql/gocql"
 	"github.com/gorilla/securecookie"
@@ -20,21 +19,63 @@ import (
 // The only session options set by this function are sessions.Options.Path to
 // "/", and sessions.Options.MaxAge to 2592000 (30 days).
 //
-// The connection to the database is not checked by this function.
+// NewCassandraStore opens config and holds onto the resulting *gocql.Session
+// for the lifetime of the store. Because the store itself opened the
+// session, Close will close it.
 func NewCassandraStore(config *gocql.ClusterConfig, tableName string, keypairs ...[]byte) (*CassandraStore, error) {
+	db, err := config.CreateSession()
+	if err != nil {
+		return nil, fmt.Errorf("cassandrastore: %v", err.Error())
+	}
+
+	c, err := NewCassandraStoreWithSession(db, tableName, keypairs...)
+	if err != nil {
+		return nil, err
+	}
+	c.ClusterConfig = config
+	c.ownsSession = true
+	return c, nil
+}
+
+// NewCassandraStoreWithSession creates, and returns a new *CassandraStore
+// backed by an already-established *gocql.Session. This is useful when the
+// caller wants to manage the session's lifecycle itself, or share a single
+// session across several stores: unlike NewCassandraStore, Close on a store
+// built this way never closes db out from under its other users — the
+// caller that opened db remains responsible for closing it. See
+// NewCassandraStore for the meaning of tableName and the default session
+// options.
+func NewCassandraStoreWithSession(db *gocql.Session, tableName string, keypairs ...[]byte) (*CassandraStore, error) {
 	if tableName == "" {
 		tableName = "sessions"
 	}
 
-	return &CassandraStore{
-		ClusterConfig: config,
-		Codecs:        securecookie.CodecsFromPairs(keypairs...),
+	c := &CassandraStore{
+		Codecs: securecookie.CodecsFromPairs(keypairs...),
 		Options: &sessions.Options{
 			Path:   "/",
 			MaxAge: 2592000,
 		},
-		TableName: tableName,
-	}, nil
+		TableName:  tableName,
+		Serializer: GobSerializer{},
+		db:         gocqlSession{db},
+	}
+	c.setQueries()
+
+	return c, nil
+}
+
+// setQueries (re)builds the query strings derived from c.TableName. It is
+// called once by the constructors; tests that build a *CassandraStore by
+// hand (around a fake cqlSession) call it too instead of duplicating the
+// query text.
+func (c *CassandraStore) setQueries() {
+	c.selectQuery = `SELECT values FROM ` + c.TableName + ` WHERE id = ?`
+	c.insertQuery = `INSERT INTO ` + c.TableName + ` (id, values) VALUES (?, ?) USING TTL ?`
+	c.insertQueryWithLastAccessed = `INSERT INTO ` + c.TableName + ` (id, values, last_accessed) VALUES (?, ?, ?) USING TTL ?`
+	c.deleteQuery = `DELETE FROM ` + c.TableName + ` WHERE id = ?`
+	c.refreshQuery = `UPDATE ` + c.TableName + ` USING TTL ? SET values = ?, last_accessed = ? WHERE id = ?`
+	c.gcSelectQuery = `SELECT id, last_accessed FROM ` + c.TableName
 }
 
 // CassandraStore stores sessions in a Cassandra database.
@@ -43,6 +84,78 @@ type CassandraStore struct {
 	Codecs        []securecookie.Codec
 	Options       *sessions.Options
 	TableName     string
+
+	// Serializer controls how session values are encoded before being
+	// written to Cassandra, and decoded when read back. It defaults to
+	// GobSerializer; set it to JSONSerializer{} for JSON encoding instead.
+	Serializer SessionSerializer
+
+	// ReadConsistency and WriteConsistency control the consistency level
+	// used for, respectively, the SELECT issued by load and the
+	// INSERT/DELETE issued by save and delete. A nil pointer (the zero
+	// value) falls back to whatever consistency c.ClusterConfig (or the
+	// session it built) was already configured with. Because
+	// gocql.Any == 0, a plain gocql.Consistency field couldn't tell
+	// "unset" apart from an explicit gocql.Any; use a pointer so
+	// gocql.Any can actually be selected, e.g.:
+	//
+	//	any := gocql.Any
+	//	c.WriteConsistency = &any
+	ReadConsistency  *gocql.Consistency
+	WriteConsistency *gocql.Consistency
+
+	// SerialConsistency controls the serial consistency level applied to
+	// queries, for future use with lightweight transactions. Its zero
+	// value likewise falls back to the cluster default.
+	SerialConsistency gocql.SerialConsistency
+
+	// RefreshOnRead, when true, rewrites a session's row (and its TTL) on
+	// every load, so actively-used sessions outlive MaxAge instead of
+	// expiring on a fixed schedule from creation.
+	RefreshOnRead bool
+
+	// GCMaxLifetime bounds how long a session may go unaccessed before
+	// StartGC's sweeper deletes it, independent of its Cassandra TTL. Zero
+	// disables the sweeper.
+	//
+	// The sessions table's last_accessed column only needs to exist once
+	// RefreshOnRead or GCMaxLifetime is actually used (see
+	// needsLastAccessed): Save leaves it out of its INSERT otherwise, so a
+	// table created before this column existed keeps working for stores
+	// that don't opt into either feature. Enabling RefreshOnRead or
+	// GCMaxLifetime against such a table requires adding the column first
+	// (e.g. ALTER TABLE ... ADD last_accessed timestamp), since
+	// EnsureSchema's CREATE TABLE IF NOT EXISTS won't alter an
+	// already-existing table.
+	GCMaxLifetime time.Duration
+
+	// GCErrorHandler, if set, is called with any error a GC sweep
+	// encounters, so a degraded cluster doesn't fail the sweeper silently.
+	// It is never called concurrently with itself. Errors are otherwise
+	// discarded.
+	GCErrorHandler func(error)
+
+	db  cqlSession
+	now func() time.Time // clock() falls back to time.Now when nil; overridden by tests.
+
+	ownsSession                 bool
+	selectQuery                 string
+	insertQuery                 string
+	insertQueryWithLastAccessed string
+	deleteQuery                 string
+	refreshQuery                string
+	gcSelectQuery               string
+}
+
+// Close releases the underlying Cassandra session, but only if this store
+// created it (i.e. it was built with NewCassandraStore). Stores built with
+// NewCassandraStoreWithSession don't own their session — since it may be
+// shared with other stores — so Close is a no-op for them; the caller that
+// created the session is responsible for closing it.
+func (c *CassandraStore) Close() {
+	if c.ownsSession {
+		c.db.Close()
+	}
 }
 
 func (c *CassandraStore) Get(r *http.Request, name string) (*sessions.Session, error) {
@@ -69,9 +182,9 @@ func (c *CassandraStore) New(r *http.Request, name string) (*sessions.Session, e
 
 func (c *CassandraStore) Save(r *http.Request, w http.ResponseWriter, s *sessions.Session) error {
 	if s.Options.MaxAge < 0 {
-		// Don't worry about deleting the sessions from Cassandra, just blow
-		// away the cookie.
-		http.SetCookie(w, sessions.NewCookie(s.Name(), "", s.Options))
+		if err := c.Delete(r, w, s); err != nil {
+			return err
+		}
 		return nil
 	}
 
@@ -93,40 +206,93 @@ func (c *CassandraStore) Save(r *http.Request, w http.ResponseWriter, s *session
 	return nil
 }
 
+// Delete removes a session from Cassandra, and clears its cookie. It is
+// called by Save whenever s.Options.MaxAge < 0, but can also be invoked
+// directly to log a session out without waiting for its TTL to expire.
+func (c *CassandraStore) Delete(r *http.Request, w http.ResponseWriter, s *sessions.Session) error {
+	if s.ID != "" {
+		if err := c.delete(s); err != nil && err != gocql.ErrNotFound {
+			return fmt.Errorf("cassandrastore: %v", err.Error())
+		}
+	}
+
+	http.SetCookie(w, sessions.NewCookie(s.Name(), "", s.Options))
+	return nil
+}
+
 // load reads in session information from the database.
 //
 // If the session exists in the database, this function will return true.
 func (c *CassandraStore) load(s *sessions.Session) error {
-	db, err := c.ClusterConfig.CreateSession()
-	if err != nil {
+	var vals []byte
+	q := c.db.Query(c.selectQuery, s.ID)
+	if err := c.applyConsistency(q, c.ReadConsistency).Scan(&vals); err != nil {
 		return fmt.Errorf("cassandrastore: %v", err.Error())
 	}
-	defer db.Close()
 
-	var vals []byte
-	q := `SELECT values FROM ` + c.TableName + ` WHERE id = ?`
-	err = db.Query(q, s.ID).Scan(&vals)
-	if err != nil {
-		return fmt.Errorf("cassandrastore: %v", err.Error())
+	if err := c.Serializer.Deserialize(vals, s); err != nil {
+		return err
+	}
+
+	if c.RefreshOnRead {
+		q := c.db.Query(c.refreshQuery, s.Options.MaxAge, vals, c.clock(), s.ID)
+		if err := c.applyConsistency(q, c.WriteConsistency).Exec(); err != nil {
+			return fmt.Errorf("cassandrastore: %v", err.Error())
+		}
 	}
 
-	dec := gob.NewDecoder(bytes.NewBuffer(vals))
-	return dec.Decode(&s.Values)
+	return nil
 }
 
 func (c *CassandraStore) save(s *sessions.Session) error {
-	db, err := c.ClusterConfig.CreateSession()
+	vals, err := c.Serializer.Serialize(s)
 	if err != nil {
 		return fmt.Errorf("cassandrastore: %v", err.Error())
 	}
-	defer db.Close()
 
-	buf := new(bytes.Buffer)
-	enc := gob.NewEncoder(buf)
-	if err = enc.Encode(s.Values); err != nil {
-		return fmt.Errorf("cassandrastore: %v", err.Error())
+	var q cqlQuery
+	if c.needsLastAccessed() {
+		q = c.db.Query(c.insertQueryWithLastAccessed, s.ID, vals, c.clock(), s.Options.MaxAge)
+	} else {
+		q = c.db.Query(c.insertQuery, s.ID, vals, s.Options.MaxAge)
+	}
+	return c.applyConsistency(q, c.WriteConsistency).Exec()
+}
+
+// needsLastAccessed reports whether c's configuration actually depends on
+// the last_accessed column: RefreshOnRead reads and rewrites it, and a GC
+// sweeper (GCMaxLifetime > 0) reads it to decide what's expired. Save uses
+// this to leave last_accessed out of its INSERT otherwise, so a sessions
+// table created before this column existed (id text PRIMARY KEY, values
+// blob) keeps working for stores that don't opt into either feature.
+func (c *CassandraStore) needsLastAccessed() bool {
+	return c.RefreshOnRead || c.GCMaxLifetime > 0
+}
+
+func (c *CassandraStore) delete(s *sessions.Session) error {
+	q := c.db.Query(c.deleteQuery, s.ID)
+	return c.applyConsistency(q, c.WriteConsistency).Exec()
+}
+
+// clock returns the current time, via c.now if a test has overridden it.
+func (c *CassandraStore) clock() time.Time {
+	if c.now != nil {
+		return c.now()
 	}
+	return time.Now()
+}
 
-	q := `INSERT INTO ` + c.TableName + ` (id, values) VALUES (?, ?) USING TTL ?`
-	return db.Query(q, s.ID, buf.Bytes(), s.Options.MaxAge).Exec()
+// applyConsistency applies consistency, and c.SerialConsistency, to q if
+// they were explicitly set, leaving q's (and therefore the cluster's)
+// default consistency alone otherwise. consistency is a pointer (rather
+// than compared against the gocql.Consistency zero value) because
+// gocql.Any == 0, and that must remain selectable as an explicit choice.
+func (c *CassandraStore) applyConsistency(q cqlQuery, consistency *gocql.Consistency) cqlQuery {
+	if consistency != nil {
+		q = q.Consistency(*consistency)
+	}
+	if c.SerialConsistency != 0 {
+		q = q.SerialConsistency(c.SerialConsistency)
+	}
+	return q
 }
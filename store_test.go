@@ -0,0 +1,185 @@
+package cassandrastore
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gocql/gocql"
+)
+
+func TestSaveThenLoadRoundTrip(t *testing.T) {
+	c, fs := newTestStore()
+
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	w := httptest.NewRecorder()
+
+	s, err := c.New(r, "session")
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	s.Values["uid"] = 42
+
+	if err := c.Save(r, w, s); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+	if len(fs.rows) != 1 {
+		t.Fatalf("rows = %d, want 1", len(fs.rows))
+	}
+	if s.ID == "" {
+		t.Fatal("Save did not assign a session ID")
+	}
+
+	// Replay the cookie Save just set against a fresh request, and confirm
+	// New loads the same values back out.
+	req2 := httptest.NewRequest(http.MethodGet, "/", nil)
+	for _, ck := range w.Result().Cookies() {
+		req2.AddCookie(ck)
+	}
+
+	s2, err := c.New(req2, "session")
+	if err != nil {
+		t.Fatalf("New (reload): %v", err)
+	}
+	if s2.ID != s.ID {
+		t.Fatalf("reloaded session ID = %q, want %q", s2.ID, s.ID)
+	}
+	if s2.Values["uid"] != 42 {
+		t.Fatalf("uid = %v, want 42", s2.Values["uid"])
+	}
+}
+
+func TestSaveWithNegativeMaxAgeDeletes(t *testing.T) {
+	c, fs := newTestStore()
+
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	w := httptest.NewRecorder()
+
+	s, err := c.New(r, "session")
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	if err := c.Save(r, w, s); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+	if len(fs.rows) != 1 {
+		t.Fatalf("rows = %d, want 1 before logout", len(fs.rows))
+	}
+
+	s.Options.MaxAge = -1
+	w2 := httptest.NewRecorder()
+	if err := c.Save(r, w2, s); err != nil {
+		t.Fatalf("Save (logout): %v", err)
+	}
+	if len(fs.rows) != 0 {
+		t.Fatalf("rows = %d, want 0 after logout", len(fs.rows))
+	}
+}
+
+func TestDeleteIsIdempotent(t *testing.T) {
+	c, _ := newTestStore()
+
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	w := httptest.NewRecorder()
+
+	s, err := c.New(r, "session")
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	s.ID = "does-not-exist"
+
+	// delete() on the fake returns gocql.ErrNotFound for a missing row;
+	// Delete must treat that as success, not surface it to the caller.
+	if err := c.Delete(r, w, s); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+}
+
+// TestSelectQueryMissingSessionReturnsErrNotFound checks the raw SELECT that
+// load issues, not load itself: load wraps the error it gets back.
+func TestSelectQueryMissingSessionReturnsErrNotFound(t *testing.T) {
+	c, _ := newTestStore()
+
+	var vals []byte
+	err := c.db.Query(c.selectQuery, "missing").Scan(&vals)
+	if err != gocql.ErrNotFound {
+		t.Fatalf("err = %v, want gocql.ErrNotFound", err)
+	}
+}
+
+// TestSaveThenLoadAgainstLegacySchema guards against a regression where
+// last_accessed was referenced by every Save/Get regardless of whether
+// RefreshOnRead or GCMaxLifetime were ever configured: a store that
+// doesn't use either must keep working against a sessions table that
+// predates that column (id text PRIMARY KEY, values blob).
+func TestSaveThenLoadAgainstLegacySchema(t *testing.T) {
+	fs := newFakeSession()
+	fs.legacySchema = true
+	c, _ := newTestStore()
+	c.db = fs
+
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	w := httptest.NewRecorder()
+
+	s, err := c.New(r, "session")
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	s.Values["uid"] = 42
+
+	if err := c.Save(r, w, s); err != nil {
+		t.Fatalf("Save against a legacy-schema table: %v", err)
+	}
+
+	req2 := httptest.NewRequest(http.MethodGet, "/", nil)
+	for _, ck := range w.Result().Cookies() {
+		req2.AddCookie(ck)
+	}
+	s2, err := c.New(req2, "session")
+	if err != nil {
+		t.Fatalf("New (reload) against a legacy-schema table: %v", err)
+	}
+	if s2.Values["uid"] != 42 {
+		t.Fatalf("uid = %v, want 42", s2.Values["uid"])
+	}
+}
+
+// TestRefreshOnReadRequiresLastAccessedColumn documents the flip side of
+// TestSaveThenLoadAgainstLegacySchema: once a store opts into
+// RefreshOnRead (or GCMaxLifetime), it does need last_accessed, so Save
+// against a legacy-schema table must fail rather than silently dropping
+// the column.
+func TestRefreshOnReadRequiresLastAccessedColumn(t *testing.T) {
+	fs := newFakeSession()
+	fs.legacySchema = true
+	c, _ := newTestStore()
+	c.db = fs
+	c.RefreshOnRead = true
+
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	w := httptest.NewRecorder()
+
+	s, err := c.New(r, "session")
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+
+	if err := c.Save(r, w, s); err == nil {
+		t.Fatal("Save succeeded against a legacy-schema table with RefreshOnRead set, want an error")
+	}
+}
+
+func TestCloseOnlyClosesOwnedSession(t *testing.T) {
+	c, fs := newTestStore()
+	c.ownsSession = false
+	c.Close()
+	if fs.closed {
+		t.Fatal("Close closed a session the store doesn't own")
+	}
+
+	c.ownsSession = true
+	c.Close()
+	if !fs.closed {
+		t.Fatal("Close did not close a session the store owns")
+	}
+}